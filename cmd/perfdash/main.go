@@ -0,0 +1,141 @@
+// Command perfdash reads a results.jsonl file written by
+// simple-db-bench's -resultDir flag and renders a static HTML page
+// with one time-series chart per benchmark name, plotted by commit so
+// a regression can be spotted and bisected.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+type record struct {
+	Commit      string  `json:"commit"`
+	Branch      string  `json:"branch"`
+	Benchmark   string  `json:"benchmark"`
+	Par         int     `json:"par"`
+	NsPerOp     float64 `json:"ns_per_op"`
+	BytesPerOp  uint64  `json:"bytes_per_op"`
+	AllocsPerOp uint64  `json:"allocs_per_op"`
+	Timestamp   string  `json:"timestamp"`
+	Hostname    string  `json:"hostname"`
+	GoVersion   string  `json:"goversion"`
+}
+
+func main() {
+	resultsPath := flag.String("results", "results.jsonl",
+		"path to a results.jsonl file written by simple-db-bench -resultDir")
+	out := flag.String("out", "perfdash.html",
+		"HTML file to write")
+	flag.Parse()
+
+	records, err := readRecords(*resultsPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	groups := groupByBenchmark(records)
+	if err := os.WriteFile(*out, []byte(renderDashboard(groups)), 0644); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("wrote %s (%d benchmarks, %d records)\n", *out, len(groups), len(records))
+}
+
+func readRecords(path string) ([]record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		var r record
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		records = append(records, r)
+	}
+	return records, scanner.Err()
+}
+
+func groupByBenchmark(records []record) map[string][]record {
+	groups := make(map[string][]record)
+	for _, r := range records {
+		groups[r.Benchmark] = append(groups[r.Benchmark], r)
+	}
+	return groups
+}
+
+func renderDashboard(groups map[string][]record) string {
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\">" +
+		"<title>simple-db-bench perfdash</title></head><body>\n")
+	b.WriteString("<h1>simple-db-bench results</h1>\n")
+	for _, name := range names {
+		b.WriteString(renderChart(name, groups[name]))
+	}
+	b.WriteString("</body></html>\n")
+	return b.String()
+}
+
+const chartWidth, chartHeight = 600, 120
+
+// renderChart draws one benchmark's ns/op over successive result
+// records (in file order, i.e. roughly commit order) as an inline SVG
+// line chart; each point's tooltip names the commit it came from.
+func renderChart(name string, points []record) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<h2>%s</h2>\n", html.EscapeString(name))
+	if len(points) == 0 {
+		return b.String()
+	}
+	maxNs := points[0].NsPerOp
+	for _, p := range points {
+		if p.NsPerOp > maxNs {
+			maxNs = p.NsPerOp
+		}
+	}
+	if maxNs == 0 {
+		maxNs = 1
+	}
+
+	fmt.Fprintf(&b, "<svg width=\"%d\" height=\"%d\" style=\"border:1px solid #ccc\">\n",
+		chartWidth, chartHeight)
+	var coords strings.Builder
+	for i, p := range points {
+		x := float64(i)/float64(maxInt(len(points)-1, 1))*float64(chartWidth-20) + 10
+		y := float64(chartHeight-10) - p.NsPerOp/maxNs*float64(chartHeight-20)
+		fmt.Fprintf(&coords, "%.1f,%.1f ", x, y)
+		fmt.Fprintf(&b, "<circle cx=\"%.1f\" cy=\"%.1f\" r=\"2\"><title>%s %s: %.0f ns/op</title></circle>\n",
+			x, y, html.EscapeString(p.Commit), html.EscapeString(p.Timestamp), p.NsPerOp)
+	}
+	fmt.Fprintf(&b, "<polyline fill=\"none\" stroke=\"steelblue\" points=\"%s\"/>\n", coords.String())
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
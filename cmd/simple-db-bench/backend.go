@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Backend is a pluggable storage engine that bencher drives through
+// Write, Read, Compact, and Fill. The native backend (below) is the
+// simple in-memory store this package has always benchmarked; bolt
+// and badger backends are optional (see backend_bolt.go and
+// backend_badger.go, enabled with the "bolt" and "badger" build tags)
+// and register themselves into backendFactories so -backend can pick
+// any of them at runtime.
+type Backend interface {
+	Put(key, val []byte) error
+	Get(key []byte) ([]byte, error)
+	Compact() error
+	Close() error
+	Name() string
+}
+
+// backendFactories holds the backends compiled into this binary,
+// keyed by the -backend flag value. Optional backends register
+// themselves here from their own (build-tagged) init functions.
+var backendFactories = map[string]func(conf config) (Backend, error){
+	"native": newNativeBackend,
+}
+
+func newBackend(conf config) (Backend, error) {
+	factory, ok := backendFactories[conf.Backend]
+	if !ok {
+		return nil, fmt.Errorf("unknown -backend %q (compiled in: %s)",
+			conf.Backend, strings.Join(backendNames(), ", "))
+	}
+	return factory(conf)
+}
+
+func backendNames() []string {
+	names := make([]string, 0, len(backendFactories))
+	for name := range backendFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// nativeBackend adapts this package's in-memory store to the Backend
+// interface.
+type nativeBackend struct {
+	s *store
+}
+
+func newNativeBackend(conf config) (Backend, error) {
+	return &nativeBackend{s: newStore()}, nil
+}
+
+func (n *nativeBackend) Put(key, val []byte) error {
+	n.s.put(string(key), val)
+	return nil
+}
+
+func (n *nativeBackend) Get(key []byte) ([]byte, error) {
+	v, _ := n.s.get(string(key))
+	return v, nil
+}
+
+func (n *nativeBackend) Compact() error {
+	n.s.compact()
+	return nil
+}
+
+func (n *nativeBackend) Close() error {
+	return nil
+}
+
+func (n *nativeBackend) Name() string {
+	return "native"
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxLatencySamples bounds the per-bencher latency ring buffer so long
+// -benchtime runs don't grow memory without bound.
+const maxLatencySamples = 200000
+
+// latencySample is one (t_start, latency, op_type) tuple.
+type latencySample struct {
+	tStartNs int64
+	latency  time.Duration
+	op       string
+}
+
+// compactionEvent records the start and end of one Compact() call, so
+// operation samples can later be classified as during-compaction or
+// quiescent.
+type compactionEvent struct {
+	startNs, endNs int64
+}
+
+// valueSize is the size in bytes of the values written by Write.
+const valueSize = 100
+
+// benchSpec is a parsed -benchtime value: either a wall-clock duration
+// to run each benchmark for, or (when the flag ends in "x") an exact
+// iteration count, mirroring go test's -benchtime.
+type benchSpec struct {
+	dur time.Duration
+	n   int
+}
+
+// fixedIters reports the exact iteration count requested by "Nx", if any.
+func (bs benchSpec) fixedIters() (int, bool) {
+	if bs.n > 0 {
+		return bs.n, true
+	}
+	return 0, false
+}
+
+type kv struct {
+	key string
+	val []byte
+}
+
+// store is a minimal two-level key-value store that emulates the
+// write-then-compact shape of an LSM-style database: recent writes
+// land in an in-memory ring buffer (rbuf), and Compact merges them
+// into a sorted, immutable table. It's exposed to bencher as the
+// "native" Backend; see backend.go.
+type store struct {
+	rbuf  map[string][]byte
+	table []kv
+}
+
+func newStore() *store {
+	return &store{rbuf: make(map[string][]byte)}
+}
+
+func (s *store) put(key string, val []byte) {
+	s.rbuf[key] = val
+}
+
+func (s *store) get(key string) ([]byte, bool) {
+	if v, ok := s.rbuf[key]; ok {
+		return v, true
+	}
+	i := sort.Search(len(s.table), func(i int) bool { return s.table[i].key >= key })
+	if i < len(s.table) && s.table[i].key == key {
+		return s.table[i].val, true
+	}
+	return nil, false
+}
+
+func (s *store) compact() {
+	for k, v := range s.rbuf {
+		i := sort.Search(len(s.table), func(i int) bool { return s.table[i].key >= k })
+		if i < len(s.table) && s.table[i].key == k {
+			s.table[i].val = v
+			continue
+		}
+		s.table = append(s.table, kv{})
+		copy(s.table[i+1:], s.table[i:])
+		s.table[i] = kv{k, v}
+	}
+	s.rbuf = make(map[string][]byte)
+}
+
+// encodeKey renders a key index as a fixed-width big-endian byte
+// string, so lexicographic key order (used by real Backend
+// implementations like bolt and badger) matches numeric order.
+func encodeKey(k uint64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, k)
+	return buf
+}
+
+func randValue(r *rand.Rand) []byte {
+	v := make([]byte, valueSize)
+	r.Read(v)
+	return v
+}
+
+// bencher drives a single named benchmark: it owns a Backend, a
+// per-thread random source, and the bookkeeping (timers, operation
+// counts, optional memory stats) needed to report results once the
+// timed section ends.
+type bencher struct {
+	conf config
+	name string
+	par  int
+	db   Backend
+	rand []*rand.Rand // one per thread, indexed by tid
+
+	start     time.Time
+	ops       int64
+	memBefore runtime.MemStats
+
+	resultElapsed time.Duration
+	resultOps     int64
+	finished      bool
+
+	// latestKey is used by the -workload benchmark's "latest" key
+	// distribution (see workload.go) to track the most recently
+	// inserted key index.
+	latestKey uint64
+
+	// sampleMu guards samples/sampleIdx/compactionEvents, which Write,
+	// Read, and startCompaction populate, possibly from multiple
+	// goroutines in a -par benchmark.
+	sampleMu         sync.Mutex
+	samples          []latencySample
+	sampleIdx        int
+	compactionEvents []compactionEvent
+
+	// compactionCount, if hasCompactionCount is set, is the number of
+	// background Compact() calls a continuous-compaction closure (see
+	// startCompaction in main.go) made during the run; finish() reports
+	// it instead of the closure printing it directly, so it's only
+	// printed once the adaptive -benchtime search has settled.
+	compactionCount    int
+	hasCompactionCount bool
+}
+
+// setCompactionCount records how many background compactions ran
+// during this attempt, for finish() to report.
+func (b *bencher) setCompactionCount(n int) {
+	b.compactionCount = n
+	b.hasCompactionCount = true
+}
+
+// recordSample adds a latency sample to the ring buffer, overwriting
+// the oldest entry once it's full.
+func (b *bencher) recordSample(op string, start time.Time, latency time.Duration) {
+	s := latencySample{tStartNs: start.UnixNano(), latency: latency, op: op}
+	b.sampleMu.Lock()
+	if len(b.samples) < maxLatencySamples {
+		b.samples = append(b.samples, s)
+	} else {
+		b.samples[b.sampleIdx%maxLatencySamples] = s
+	}
+	b.sampleIdx++
+	b.sampleMu.Unlock()
+}
+
+// recordCompactionEvent logs one Compact() call's start and end time.
+func (b *bencher) recordCompactionEvent(start, end time.Time) {
+	b.sampleMu.Lock()
+	b.compactionEvents = append(b.compactionEvents, compactionEvent{
+		startNs: start.UnixNano(),
+		endNs:   end.UnixNano(),
+	})
+	b.sampleMu.Unlock()
+}
+
+func newBench(conf config, name string, par int) *bencher {
+	rs := make([]*rand.Rand, par)
+	for i := range rs {
+		rs[i] = rand.New(rand.NewSource(int64(i) + 1))
+	}
+	db, err := newBackend(conf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	b := &bencher{
+		conf:  conf,
+		name:  name,
+		par:   par,
+		db:    db,
+		rand:  rs,
+		start: time.Now(),
+	}
+	if conf.BenchMem {
+		b.memBefore = readMemStats()
+	}
+	return b
+}
+
+func (b *bencher) Write(tid int) error {
+	r := b.rand[tid]
+	key := uint64(r.Intn(b.conf.DatabaseSize))
+	start := time.Now()
+	err := b.db.Put(encodeKey(key), randValue(r))
+	b.recordSample("write", start, time.Since(start))
+	return err
+}
+
+func (b *bencher) Read(tid int) error {
+	r := b.rand[tid]
+	key := uint64(r.Intn(b.conf.DatabaseSize))
+	start := time.Now()
+	_, err := b.db.Get(encodeKey(key))
+	b.recordSample("read", start, time.Since(start))
+	return err
+}
+
+func (b *bencher) Compact() {
+	if err := b.db.Compact(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Fill populates the store with DatabaseSize keys directly, without
+// going through the timed Write path, then compacts them into the
+// table so that subsequent reads exercise "table reads" rather than
+// the rbuf.
+func (b *bencher) Fill() {
+	r := rand.New(rand.NewSource(0))
+	for i := 0; i < b.conf.DatabaseSize; i++ {
+		if err := b.db.Put(encodeKey(uint64(i)), randValue(r)); err != nil {
+			log.Fatal(err)
+		}
+	}
+	b.Compact()
+}
+
+// Reset restarts the timer (op count, memory-stats baseline, and
+// latency samples) so that untimed setup work (e.g., Fill) doesn't
+// count against the benchmark.
+func (b *bencher) Reset() {
+	b.start = time.Now()
+	atomic.StoreInt64(&b.ops, 0)
+	if b.conf.BenchMem {
+		b.memBefore = readMemStats()
+	}
+	b.sampleMu.Lock()
+	b.samples = nil
+	b.sampleIdx = 0
+	b.compactionEvents = nil
+	b.sampleMu.Unlock()
+}
+
+func (b *bencher) finishOp(tid int, err error) {
+	if err != nil {
+		log.Fatal(err)
+	}
+	atomic.AddInt64(&b.ops, 1)
+}
+
+func (b *bencher) IsFinished() bool {
+	return b.finished
+}
+
+// readMemStats forces a GC for stable numbers and returns the current
+// memory statistics, mirroring what testing.B does for -benchmem.
+func readMemStats() runtime.MemStats {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m
+}
+
+var benchNameReplacer = strings.NewReplacer(" ", "_", "(", "", ")", "")
+
+// benchName turns a human-readable bench name like "table reads (par=4)"
+// into a Go-benchmark-style name with no spaces, as benchstat expects.
+func benchName(name string) string {
+	return benchNameReplacer.Replace(name)
+}
+
+// finish stops the timer and prints a benchstat-compatible result line:
+//
+//	BenchmarkName/backend-par  N  ns/op  [B/op  allocs/op]
+//
+// Including the backend name lets benchstat compare engines (native,
+// bolt, badger, ...) from a single binary's output.
+func (b *bencher) finish() {
+	b.resultElapsed = time.Since(b.start)
+	b.resultOps = atomic.LoadInt64(&b.ops)
+	var nsPerOp float64
+	if b.resultOps > 0 {
+		nsPerOp = float64(b.resultElapsed.Nanoseconds()) / float64(b.resultOps)
+	}
+	line := fmt.Sprintf("Benchmark%s/%s-%d\t%d\t%.2f ns/op",
+		benchName(b.name), b.db.Name(), b.par, b.resultOps, nsPerOp)
+	var bytesPerOp, allocsPerOp uint64
+	if b.conf.BenchMem && b.resultOps > 0 {
+		after := readMemStats()
+		bytesPerOp = (after.TotalAlloc - b.memBefore.TotalAlloc) / uint64(b.resultOps)
+		allocsPerOp = (after.Mallocs - b.memBefore.Mallocs) / uint64(b.resultOps)
+		line += fmt.Sprintf("\t%d B/op\t%d allocs/op", bytesPerOp, allocsPerOp)
+	}
+	fmt.Println(line)
+	if b.hasCompactionCount {
+		fmt.Printf("  finished %d compactions\n", b.compactionCount)
+	}
+
+	hists := b.opHistograms()
+	for _, op := range sortedKeys(hists) {
+		fmt.Printf("  %s: %s\n", op, hists[op].summary())
+	}
+	if len(b.compactionEvents) > 0 {
+		during, quiescent := b.compactionSummary()
+		fmt.Printf("  during-compaction (n=%d): %s\n", during.count, during.summary())
+		fmt.Printf("  quiescent (n=%d): %s\n", quiescent.count, quiescent.summary())
+	}
+	if err := b.writeLatencyLog(); err != nil {
+		log.Printf("could not write %s latency log: %v", b.name, err)
+	}
+
+	b.recordResult(nsPerOp, bytesPerOp, allocsPerOp)
+	b.finished = true
+}
+
+func (b *bencher) stop() {
+	if err := b.db.Close(); err != nil {
+		log.Print(err)
+	}
+	os.RemoveAll(b.conf.DatabaseDir)
+}
+
+// attempt runs one pass of f with n iterations, resetting the timer,
+// op count, and finished state beforehand. Closures are not expected
+// to call finish() themselves (any extra info, like a background
+// compaction count, should go through a setter such as
+// setCompactionCount and let finish() print it) so that runAdaptive
+// below can tell how long the pass took without printing or recording
+// a result for every warm-up attempt. attempt returns the elapsed
+// time and op count for the pass.
+func (b *bencher) attempt(f func(b *bencher, n int), n int) (time.Duration, int64) {
+	b.finished = false
+	b.start = time.Now()
+	atomic.StoreInt64(&b.ops, 0)
+	if b.conf.BenchMem {
+		b.memBefore = readMemStats()
+	}
+	b.sampleMu.Lock()
+	b.samples = nil
+	b.sampleIdx = 0
+	b.compactionEvents = nil
+	b.sampleMu.Unlock()
+	f(b, n)
+	if !b.finished {
+		b.resultElapsed = time.Since(b.start)
+		b.resultOps = atomic.LoadInt64(&b.ops)
+	}
+	return b.resultElapsed, b.resultOps
+}
+
+// runAdaptive runs f, choosing an iteration count n per -benchtime:
+// either a fixed count ("1000000x"), or, for a duration like "5s", by
+// growing n geometrically (capped at 100x per step, like testing.B)
+// from a small starting point until the timed pass takes at least that
+// long. Because each pass re-invokes f from scratch, any untimed setup
+// a closure does before calling Reset (e.g. Fill) reruns too, same as
+// testing.B without ResetTimer.
+func (b *bencher) runAdaptive(f func(b *bencher, n int)) {
+	if n, ok := b.conf.BenchTime.fixedIters(); ok {
+		b.attempt(f, n)
+		if !b.finished {
+			b.finish()
+		}
+		return
+	}
+	target := b.conf.BenchTime.dur
+	n := 100
+	for {
+		elapsed, _ := b.attempt(f, n)
+		if elapsed >= target {
+			if !b.finished {
+				b.finish()
+			}
+			return
+		}
+		grow := float64(target) / float64(elapsed)
+		if grow > 100 {
+			grow = 100
+		}
+		if grow < 1.1 {
+			grow = 1.1
+		}
+		n = int(float64(n) * grow)
+		if n < 1 {
+			n = 1
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"math/bits"
+	"sort"
+	"time"
+)
+
+// subBuckets is the number of linearly-spaced buckets within each
+// power-of-two range, giving ~10% resolution per bucket: a log-linear
+// layout in the style of HdrHistogram, cheap enough to keep one per
+// op type without needing the real dependency.
+const subBuckets = 10
+
+// histogram is a fixed-size log-linear latency histogram: buckets are
+// exponentially spaced by power of two, each subdivided linearly, so
+// it can represent nanoseconds to seconds with bounded memory and
+// without needing to know the value range up front.
+type histogram struct {
+	buckets [64 * subBuckets]uint64
+	count   uint64
+}
+
+// bucketFor maps a duration to its bucket index.
+func bucketFor(d time.Duration) int {
+	ns := int64(d)
+	if ns < 1 {
+		ns = 1
+	}
+	e := bits.Len64(uint64(ns)) - 1
+	base := int64(1) << uint(e)
+	next := base << 1
+	frac := float64(ns-base) / float64(next-base)
+	sub := int(frac * subBuckets)
+	if sub >= subBuckets {
+		sub = subBuckets - 1
+	}
+	return e*subBuckets + sub
+}
+
+// bucketMid returns a representative duration for a bucket index, used
+// when reporting a percentile.
+func bucketMid(idx int) time.Duration {
+	e := idx / subBuckets
+	sub := idx % subBuckets
+	base := int64(1) << uint(e)
+	next := base << 1
+	lo := base + (next-base)*int64(sub)/subBuckets
+	hi := base + (next-base)*int64(sub+1)/subBuckets
+	return time.Duration((lo + hi) / 2)
+}
+
+func (h *histogram) record(d time.Duration) {
+	idx := bucketFor(d)
+	if idx >= len(h.buckets) {
+		idx = len(h.buckets) - 1
+	}
+	h.buckets[idx]++
+	h.count++
+}
+
+// percentile returns the latency at percentile p (0..100).
+func (h *histogram) percentile(p float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	target := uint64(p / 100 * float64(h.count))
+	var cum uint64
+	for idx, c := range h.buckets {
+		cum += c
+		if cum > target {
+			return bucketMid(idx)
+		}
+	}
+	return bucketMid(len(h.buckets) - 1)
+}
+
+// summary formats the common p50/p95/p99 triple for a log line.
+func (h *histogram) summary() string {
+	return "p50=" + h.percentile(50).String() +
+		" p95=" + h.percentile(95).String() +
+		" p99=" + h.percentile(99).String()
+}
+
+// sortedKeys is a small helper used when printing per-op-type
+// histograms in a stable order.
+func sortedKeys(m map[string]*histogram) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// opHistograms buckets recorded latency samples by op type
+// (read/write/insert/rmw), so finish() can report each one's
+// percentiles separately instead of a single average.
+func (b *bencher) opHistograms() map[string]*histogram {
+	hists := make(map[string]*histogram)
+	b.sampleMu.Lock()
+	defer b.sampleMu.Unlock()
+	for _, s := range b.samples {
+		h, ok := hists[s.op]
+		if !ok {
+			h = &histogram{}
+			hists[s.op] = h
+		}
+		h.record(s.latency)
+	}
+	return hists
+}
+
+// duringCompaction reports whether sample s started while some
+// recorded Compact() call was still running.
+func (b *bencher) duringCompaction(s latencySample) bool {
+	end := s.tStartNs + s.latency.Nanoseconds()
+	for _, c := range b.compactionEvents {
+		if s.tStartNs < c.endNs && end > c.startNs {
+			return true
+		}
+	}
+	return false
+}
+
+// compactionSummary splits recorded op latencies into two histograms,
+// one for ops that overlapped a recorded Compact() call and one for
+// ops that didn't, so the two can be reported (and compared) separately.
+func (b *bencher) compactionSummary() (during, quiescent *histogram) {
+	during, quiescent = &histogram{}, &histogram{}
+	b.sampleMu.Lock()
+	defer b.sampleMu.Unlock()
+	for _, s := range b.samples {
+		if b.duringCompaction(s) {
+			during.record(s.latency)
+		} else {
+			quiescent.record(s.latency)
+		}
+	}
+	return during, quiescent
+}
+
+// writeLatencyLog writes every recorded op sample and compaction
+// event to <resultDir>/<bench>.latencies.tsv, one row each as
+// t_start_ns\tlatency_ns\top (op is "compaction" for a compaction
+// event), so they can be correlated after the fact.
+func (b *bencher) writeLatencyLog() error {
+	if b.conf.ResultDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(b.conf.ResultDir, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(b.conf.ResultDir, benchName(b.name)+".latencies.tsv")
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	fmt.Fprintln(w, "t_start_ns\tlatency_ns\top")
+	b.sampleMu.Lock()
+	for _, s := range b.samples {
+		fmt.Fprintf(w, "%d\t%d\t%s\n", s.tStartNs, s.latency.Nanoseconds(), s.op)
+	}
+	for _, c := range b.compactionEvents {
+		fmt.Fprintf(w, "%d\t%d\tcompaction\n", c.startNs, c.endNs-c.startNs)
+	}
+	b.sampleMu.Unlock()
+	return w.Flush()
+}
@@ -8,6 +8,9 @@ import (
 	"regexp"
 	"runtime"
 	"runtime/pprof"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type config struct {
@@ -15,9 +18,17 @@ type config struct {
 	DatabaseSize int
 	BenchFilter  *regexp.Regexp
 	ListBenches  bool
+	BenchCount   int
+	BenchMem     bool
+	BenchTime    benchSpec
+	Backend      string
+	Workload     string
+	Distribution string
+	ZipfTheta    float64
+	ResultDir    string
 }
 
-func (conf config) runBench(name string, par int, f func(b *bencher)) {
+func (conf config) runBench(name string, par int, f func(b *bencher, n int)) {
 	if !conf.BenchFilter.MatchString(name) {
 		return
 	}
@@ -25,12 +36,33 @@ func (conf config) runBench(name string, par int, f func(b *bencher)) {
 		fmt.Println(name)
 		return
 	}
-	b := newBench(conf, name, par)
-	f(&b)
-	if !b.IsFinished() {
-		b.finish()
+	count := conf.BenchCount
+	if count < 1 {
+		count = 1
+	}
+	for i := 0; i < count; i++ {
+		b := newBench(conf, name, par)
+		b.runAdaptive(f)
+		b.stop()
 	}
-	b.stop()
+}
+
+// parseBenchTime parses a -benchtime value: a duration like "5s" (run
+// each benchmark for approximately that long), or an exact iteration
+// count like "1000000x" (run exactly that many iterations).
+func parseBenchTime(s string) (benchSpec, error) {
+	if n := strings.TrimSuffix(s, "x"); n != s {
+		iters, err := strconv.Atoi(n)
+		if err != nil || iters <= 0 {
+			return benchSpec{}, fmt.Errorf("invalid -benchtime %q: not a positive iteration count", s)
+		}
+		return benchSpec{n: iters}, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return benchSpec{}, fmt.Errorf("invalid -benchtime %q: %v", s, err)
+	}
+	return benchSpec{dur: d}, nil
 }
 
 // startCompaction starts running compactions continuously
@@ -48,7 +80,9 @@ func startCompaction(b *bencher) (done chan int) {
 			case done <- numCompactions:
 				return
 			default:
+				start := time.Now()
 				b.Compact()
+				b.recordCompactionEvent(start, time.Now())
 				numCompactions++
 			}
 		}
@@ -78,9 +112,22 @@ func main() {
 		"list (matching) benchmarks without running them")
 	filterString := flag.String("run", "",
 		"regex to BenchFilter benchmarks (empty string means run all)")
-	var kiters int
-	flag.IntVar(&kiters, "kiters", 1000,
-		"thousands of iterations to run")
+	benchtimeString := flag.String("benchtime", "1s",
+		"run each benchmark for this long (e.g. 5s), or exactly this many iterations (e.g. 1000000x)")
+	flag.IntVar(&conf.BenchCount, "count", 1,
+		"run each benchmark N times (like go test -count)")
+	flag.BoolVar(&conf.BenchMem, "benchmem", false,
+		"report memory allocations (B/op, allocs/op) alongside ns/op")
+	flag.StringVar(&conf.Backend, "backend", "native",
+		"storage backend to benchmark (compiled in: "+strings.Join(backendNames(), ", ")+")")
+	flag.StringVar(&conf.Workload, "workload", "B",
+		"YCSB-style operation mix to run: A, B, C, D, or F")
+	flag.StringVar(&conf.Distribution, "distribution", "uniform",
+		"key distribution for the -workload benchmark: uniform, zipf, or latest")
+	flag.Float64Var(&conf.ZipfTheta, "zipfTheta", 0.99,
+		"skew for the zipf/latest key distributions")
+	flag.StringVar(&conf.ResultDir, "resultDir", "",
+		"if set, append a JSON result record to <resultDir>/results.jsonl after each benchmark")
 	var par int
 	flag.IntVar(&par, "par", 2,
 		"number of concurrent threads for concurrent benchmarks")
@@ -115,53 +162,58 @@ func main() {
 		defer writeMemProfile(*memprofile)
 	}
 
-	conf.runBench("writes", 1, func(b *bencher) {
-		for i := 0; i < 1000*kiters; i++ {
+	var err error
+	conf.BenchTime, err = parseBenchTime(*benchtimeString)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	conf.runBench("writes", 1, func(b *bencher, n int) {
+		for i := 0; i < n; i++ {
 			b.finishOp(0, b.Write(0))
 		}
 		b.Compact()
 	})
 
-	conf.runBench("write + compact", 1, func(b *bencher) {
+	conf.runBench("write + compact", 1, func(b *bencher, n int) {
 		b.Fill()
 		b.Reset()
 		stopCompaction := startCompaction(b)
-		for i := 0; i < 1000*kiters; i++ {
+		for i := 0; i < n; i++ {
 			b.finishOp(0, b.Write(0))
 		}
-		b.finish()
-		numCompactions := <-stopCompaction
-		fmt.Printf("  finished %d compactions\n", numCompactions)
+		b.setCompactionCount(<-stopCompaction)
 	})
 
-	conf.runBench("rbuf reads", 1, func(b *bencher) {
+	conf.runBench("rbuf reads", 1, func(b *bencher, n int) {
 		b.Fill()
 		b.Reset()
-		for i := 0; i < 1000*kiters; i++ {
+		for i := 0; i < n; i++ {
 			b.finishOp(0, b.Read(0))
 		}
 	})
-	conf.runBench("table reads", 1, func(b *bencher) {
+	conf.runBench("table reads", 1, func(b *bencher, n int) {
 		b.Fill()
 		b.Compact()
 		b.Compact()
 		b.Reset()
-		for i := 0; i < 1000*kiters; i++ {
+		for i := 0; i < n; i++ {
 			b.finishOp(0, b.Read(0))
 		}
 	})
 
 	conf.runBench(fmt.Sprintf("table reads (par=%d)", par),
 		par,
-		func(b *bencher) {
+		func(b *bencher, n int) {
 			b.Fill()
 			b.Compact()
 			b.Compact()
 			b.Reset()
+			perThread := n / par
 			done := make(chan bool)
 			for tid := 0; tid < par; tid++ {
 				go func(tid int) {
-					for i := 0; i < 1000*kiters; i++ {
+					for i := 0; i < perThread; i++ {
 						b.finishOp(tid, b.Read(tid))
 					}
 					done <- true
@@ -174,14 +226,15 @@ func main() {
 
 	conf.runBench(fmt.Sprintf("rbuf reads (par=%d)", par),
 		par,
-		func(b *bencher) {
+		func(b *bencher, n int) {
 			b.Fill()
 			b.Compact()
 			b.Reset()
+			perThread := n / par
 			done := make(chan bool)
 			for tid := 0; tid < par; tid++ {
 				go func(tid int) {
-					for i := 0; i < 1000*kiters; i++ {
+					for i := 0; i < perThread; i++ {
 						b.finishOp(tid, b.Read(tid))
 					}
 					done <- true
@@ -194,15 +247,16 @@ func main() {
 
 	conf.runBench(fmt.Sprintf("read par=%d + compact", par),
 		par,
-		func(b *bencher) {
+		func(b *bencher, n int) {
 			b.Fill()
 			b.Compact()
 			b.Reset()
 			stopCompaction := startCompaction(b)
+			perThread := n / par
 			done := make(chan bool)
 			for tid := 0; tid < par; tid++ {
 				go func(tid int) {
-					for i := 0; i < 1000*kiters; i++ {
+					for i := 0; i < perThread; i++ {
 						b.finishOp(tid, b.Read(tid))
 					}
 					done <- true
@@ -211,8 +265,13 @@ func main() {
 			for tid := 0; tid < par; tid++ {
 				<-done
 			}
-			b.finish()
-			numCompactions := <-stopCompaction
-			fmt.Printf("  finished %d compactions\n", numCompactions)
+			b.setCompactionCount(<-stopCompaction)
+		})
+
+	conf.runBench(fmt.Sprintf("ycsb %s (dist=%s)", conf.Workload, conf.Distribution), 1,
+		func(b *bencher, n int) {
+			b.Fill()
+			b.Reset()
+			b.runWorkload(n)
 		})
 }
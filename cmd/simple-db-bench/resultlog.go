@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// resultRecord is one line appended to <resultDir>/results.jsonl after
+// each benchmark; cmd/perfdash reads these back to draw its charts.
+type resultRecord struct {
+	Commit      string  `json:"commit"`
+	Branch      string  `json:"branch"`
+	Benchmark   string  `json:"benchmark"`
+	Par         int     `json:"par"`
+	NsPerOp     float64 `json:"ns_per_op"`
+	BytesPerOp  uint64  `json:"bytes_per_op"`
+	AllocsPerOp uint64  `json:"allocs_per_op"`
+	Timestamp   string  `json:"timestamp"`
+	Hostname    string  `json:"hostname"`
+	GoVersion   string  `json:"goversion"`
+}
+
+var (
+	gitMetaOnce          sync.Once
+	gitCommit, gitBranch string
+)
+
+// gitMetadata shells out to git once (cached for the process lifetime)
+// to find the current commit and branch, for stamping result records.
+func gitMetadata() (commit, branch string) {
+	gitMetaOnce.Do(func() {
+		gitCommit = gitRevParse("HEAD")
+		gitBranch = gitRevParse("--abbrev-ref", "HEAD")
+	})
+	return gitCommit, gitBranch
+}
+
+func gitRevParse(args ...string) string {
+	out, err := exec.Command("git", append([]string{"rev-parse"}, args...)...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// appendResult appends rec as one JSON line to <dir>/results.jsonl,
+// creating dir if needed. It's a no-op if dir is empty (-resultDir
+// unset).
+func appendResult(dir string, rec resultRecord) error {
+	if dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, "results.jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(rec)
+}
+
+// recordResult builds and appends a resultRecord for this bencher's
+// just-finished run, using the ns/op and (if -benchmem) B/op and
+// allocs/op already computed by finish().
+func (b *bencher) recordResult(nsPerOp float64, bytesPerOp, allocsPerOp uint64) {
+	if b.conf.ResultDir == "" {
+		return
+	}
+	commit, branch := gitMetadata()
+	hostname, _ := os.Hostname()
+	rec := resultRecord{
+		Commit:      commit,
+		Branch:      branch,
+		Benchmark:   fmt.Sprintf("%s/%s", benchName(b.name), b.db.Name()),
+		Par:         b.par,
+		NsPerOp:     nsPerOp,
+		BytesPerOp:  bytesPerOp,
+		AllocsPerOp: allocsPerOp,
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		Hostname:    hostname,
+		GoVersion:   runtime.Version(),
+	}
+	if err := appendResult(b.conf.ResultDir, rec); err != nil {
+		log.Printf("could not append to %s/results.jsonl: %v", b.conf.ResultDir, err)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// keyGen produces key indices in [0, DatabaseSize) according to some
+// access distribution.
+type keyGen interface {
+	Uint64() uint64
+}
+
+type uniformGen struct {
+	r *rand.Rand
+	n uint64
+}
+
+func (g uniformGen) Uint64() uint64 {
+	return uint64(g.r.Int63n(int64(g.n)))
+}
+
+// zipfGen wraps math/rand's Zipf generator. YCSB's "zipfian" generator
+// is parameterized by a skew theta in (0, 1) (default 0.99); Go's Zipf
+// wants an s strictly greater than 1, so we map theta to s = 1 + theta.
+type zipfGen struct {
+	z *rand.Zipf
+}
+
+func newZipfGen(r *rand.Rand, theta float64, n uint64) zipfGen {
+	return zipfGen{z: rand.NewZipf(r, 1+theta, 1, n-1)}
+}
+
+func (g zipfGen) Uint64() uint64 {
+	return g.z.Uint64()
+}
+
+// latestGen biases toward recently-inserted keys by drawing a
+// Zipf-distributed "age" back from the most recently written key,
+// which YCSB's "latest" distribution uses for workload D.
+type latestGen struct {
+	z      *rand.Zipf
+	latest *uint64
+}
+
+func newLatestGen(r *rand.Rand, theta float64, n uint64, latest *uint64) latestGen {
+	return latestGen{z: rand.NewZipf(r, 1+theta, 1, n-1), latest: latest}
+}
+
+func (g latestGen) Uint64() uint64 {
+	age := g.z.Uint64()
+	if age > *g.latest {
+		return 0
+	}
+	return *g.latest - age
+}
+
+func newKeyGen(dist string, r *rand.Rand, theta float64, size uint64, latest *uint64) (keyGen, error) {
+	switch dist {
+	case "", "uniform":
+		return uniformGen{r: r, n: size}, nil
+	case "zipf", "latest":
+		if theta <= 0 {
+			return nil, fmt.Errorf("invalid -zipfTheta %v: must be > 0", theta)
+		}
+		if dist == "zipf" {
+			return newZipfGen(r, theta, size), nil
+		}
+		return newLatestGen(r, theta, size, latest), nil
+	default:
+		return nil, fmt.Errorf("unknown -distribution %q (want uniform, zipf, or latest)", dist)
+	}
+}
+
+// workloadMix is a YCSB-style operation mix: the fraction of
+// operations that should be reads, updates (overwrite an existing
+// key), inserts (write a new key), and read-modify-writes.
+type workloadMix struct {
+	read, update, insert, rmw float64
+}
+
+var workloadMixes = map[string]workloadMix{
+	"A": {read: 0.5, update: 0.5},
+	"B": {read: 0.95, update: 0.05},
+	"C": {read: 1.0},
+	"D": {read: 0.95, insert: 0.05},
+	"F": {read: 0.5, rmw: 0.5},
+}
+
+// op picks an operation type for one iteration according to the mix.
+func (w workloadMix) op(r *rand.Rand) string {
+	x := r.Float64()
+	if x < w.read {
+		return "read"
+	}
+	x -= w.read
+	if x < w.update {
+		return "write"
+	}
+	x -= w.update
+	if x < w.insert {
+		return "insert"
+	}
+	return "rmw"
+}
+
+// runWorkload runs n operations drawn from -workload's mix, with keys
+// drawn from -distribution, recording each op's latency by type so
+// finish() can report read/write/rmw percentiles separately.
+func (b *bencher) runWorkload(n int) {
+	mix, ok := workloadMixes[b.conf.Workload]
+	if !ok {
+		log.Fatalf("unknown -workload %q (want one of A, B, C, D, F)", b.conf.Workload)
+	}
+	r := b.rand[0]
+	atomic.StoreUint64(&b.latestKey, uint64(b.conf.DatabaseSize-1))
+	keys, err := newKeyGen(b.conf.Distribution, r, b.conf.ZipfTheta, uint64(b.conf.DatabaseSize), &b.latestKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		op := mix.op(r)
+		key := encodeKey(keys.Uint64())
+		start := time.Now()
+		var opErr error
+		switch op {
+		case "read":
+			_, opErr = b.db.Get(key)
+		case "write":
+			opErr = b.db.Put(key, randValue(r))
+		case "insert":
+			newKey := atomic.AddUint64(&b.latestKey, 1)
+			opErr = b.db.Put(encodeKey(newKey), randValue(r))
+		case "rmw":
+			_, opErr = b.db.Get(key)
+			if opErr == nil {
+				opErr = b.db.Put(key, randValue(r))
+			}
+		}
+		b.finishOp(0, opErr)
+		b.recordSample(op, start, time.Since(start))
+	}
+}